@@ -0,0 +1,68 @@
+package helper
+
+import "fmt"
+
+// maxHealthProbeBudgetSeconds is the effective request budget Front Door allows for a backend pool's
+// combined health probe traffic
+const maxHealthProbeBudgetSeconds = 255
+
+// DoesBackendPoolExists validates that a backend pool name referenced elsewhere in the configuration
+// (e.g. by a routing rule's forwarding_configuration) is actually defined by a "backend_pool" block
+func DoesBackendPoolExists(backendPoolName string, backendPools []interface{}) error {
+	for _, bps := range backendPools {
+		backendPool := bps.(map[string]interface{})
+		if backendPool["name"].(string) == backendPoolName {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`"backend_pool_name":%q was not found in the configuration file. verify you have the "backend_pool":%q defined in the configuration file`, backendPoolName, backendPoolName)
+}
+
+// AzureKeyVaultCertificateHasValues returns whether all (shouldHaveValues true) or none (shouldHaveValues
+// false) of the Azure Key Vault certificate fields are set on a custom_https_configuration block
+func AzureKeyVaultCertificateHasValues(customHttpsConfiguration map[string]interface{}, shouldHaveValues bool) bool {
+	secretName := customHttpsConfiguration["azure_key_vault_certificate_secret_name"].(string)
+	secretVersion := customHttpsConfiguration["azure_key_vault_certificate_secret_version"].(string)
+	vaultID := customHttpsConfiguration["azure_key_vault_certificate_vault_id"].(string)
+
+	if shouldHaveValues {
+		return secretName != "" && secretVersion != "" && vaultID != ""
+	}
+
+	return secretName == "" && secretVersion == "" && vaultID == ""
+}
+
+// ValidateHealthProbeForPool cross-checks a backend_pool_health_probe against the backend_pool that
+// references it: an "Http" probe must not target a pool with a backend that only defines "https_port", a HEAD
+// probe must declare an explicit path, and the probe's effective budget (interval * backend count) must fit within
+// Front Door's health probe request budget
+func ValidateHealthProbeForPool(healthProbe map[string]interface{}, backendPool map[string]interface{}) error {
+	probeName := healthProbe["name"].(string)
+	protocol := healthProbe["protocol"].(string)
+	path := healthProbe["path"].(string)
+	probeMethod := healthProbe["probe_method"].(string)
+	interval := healthProbe["interval_in_seconds"].(int)
+
+	backendPoolName := backendPool["name"].(string)
+	backends := backendPool["backend"].([]interface{})
+
+	if protocol == "Http" {
+		for _, b := range backends {
+			backend := b.(map[string]interface{})
+			if backend["http_port"].(int) == 0 && backend["https_port"].(int) > 0 {
+				return fmt.Errorf(`"backend_pool_health_probe":%q is invalid, its "protocol" is "Http" but "backend_pool":%q has a "backend" that only defines "https_port", disabling http`, probeName, backendPoolName)
+			}
+		}
+	}
+
+	if path == "" && probeMethod == "HEAD" {
+		return fmt.Errorf(`"backend_pool_health_probe":%q is invalid, "path" must not be empty when "probe_method" is "HEAD"`, probeName)
+	}
+
+	if budget := interval * len(backends); budget > maxHealthProbeBudgetSeconds {
+		return fmt.Errorf(`"backend_pool_health_probe":%q is invalid, "interval_in_seconds" (%d) multiplied by the %d backends in "backend_pool":%q is %d seconds, which exceeds Front Door's %d second health probe budget`, probeName, interval, len(backends), backendPoolName, budget, maxHealthProbeBudgetSeconds)
+	}
+
+	return nil
+}
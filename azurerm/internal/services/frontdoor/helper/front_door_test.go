@@ -0,0 +1,97 @@
+package helper
+
+import "testing"
+
+func TestValidateHealthProbeForPool(t *testing.T) {
+	healthProbe := func(protocol, path, probeMethod string, interval int) map[string]interface{} {
+		return map[string]interface{}{
+			"name":                "probe1",
+			"protocol":            protocol,
+			"path":                path,
+			"probe_method":        probeMethod,
+			"interval_in_seconds": interval,
+		}
+	}
+
+	backend := func(httpPort, httpsPort int) map[string]interface{} {
+		return map[string]interface{}{
+			"http_port":  httpPort,
+			"https_port": httpsPort,
+		}
+	}
+
+	backendPool := func(backends ...map[string]interface{}) map[string]interface{} {
+		items := make([]interface{}, len(backends))
+		for i, b := range backends {
+			items[i] = b
+		}
+
+		return map[string]interface{}{
+			"name":    "pool1",
+			"backend": items,
+		}
+	}
+
+	cases := []struct {
+		Name        string
+		HealthProbe map[string]interface{}
+		BackendPool map[string]interface{}
+		ExpectErr   bool
+	}{
+		{
+			Name:        "valid http probe against a backend serving http",
+			HealthProbe: healthProbe("Http", "/", "GET", 30),
+			BackendPool: backendPool(backend(80, 443)),
+			ExpectErr:   false,
+		},
+		{
+			Name:        "http probe against a backend that only serves https",
+			HealthProbe: healthProbe("Http", "/", "GET", 30),
+			BackendPool: backendPool(backend(0, 443)),
+			ExpectErr:   true,
+		},
+		{
+			Name:        "https probe against a backend that only serves https is valid",
+			HealthProbe: healthProbe("Https", "/", "GET", 30),
+			BackendPool: backendPool(backend(0, 443)),
+			ExpectErr:   false,
+		},
+		{
+			Name:        "HEAD probe method with an empty path",
+			HealthProbe: healthProbe("Http", "", "HEAD", 30),
+			BackendPool: backendPool(backend(80, 443)),
+			ExpectErr:   true,
+		},
+		{
+			Name:        "HEAD probe method with a path is valid",
+			HealthProbe: healthProbe("Http", "/", "HEAD", 30),
+			BackendPool: backendPool(backend(80, 443)),
+			ExpectErr:   false,
+		},
+		{
+			Name:        "interval multiplied by backend count exceeds the health probe budget",
+			HealthProbe: healthProbe("Http", "/", "GET", 100),
+			BackendPool: backendPool(backend(80, 443), backend(80, 443), backend(80, 443)),
+			ExpectErr:   true,
+		},
+		{
+			Name:        "interval multiplied by backend count fits within the health probe budget",
+			HealthProbe: healthProbe("Http", "/", "GET", 60),
+			BackendPool: backendPool(backend(80, 443), backend(80, 443), backend(80, 443)),
+			ExpectErr:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := ValidateHealthProbeForPool(tc.HealthProbe, tc.BackendPool)
+
+			if tc.ExpectErr && err == nil {
+				t.Fatalf("expected an error but got none")
+			}
+			if !tc.ExpectErr && err != nil {
+				t.Fatalf("expected no error but got: %+v", err)
+			}
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package validate
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"log"
+	"net/http"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/Azure/azure-sdk-for-go/services/preview/frontdoor/mgmt/2019-04-01/frontdoor"
 	"github.com/hashicorp/terraform/helper/schema"
@@ -10,6 +15,10 @@ import (
 	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/services/frontdoor/helper"
 )
 
+// customBlockResponseBodyMaxBytes is the maximum decoded size Front Door's WAF accepts for a
+// custom_block_response_body (32 KB)
+const customBlockResponseBodyMaxBytes = 32 * 1024
+
 //Frontdoor name must begin with a letter or number, end with a letter or number and may contain only letters, numbers or hyphens.
 func FrontDoorName(i interface{}, k string) (_ []string, errors []error) {
 	if m, regexErrs := validate.RegExHelper(i, k, `(^[\da-zA-Z])([-\da-zA-Z]{3,61})([\da-zA-Z]$)`); !m {
@@ -28,12 +37,83 @@ func BackendPoolRoutingRuleName(i interface{}, k string) (_ []string, errors []e
 }
 
 func CustomBlockResponseBody(i interface{}, k string) (_ []string, errors []error) {
-	if m, regexErrs := validate.RegExHelper(i, k, `^(?:[A-Za-z0-9+/]{4})*(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=|[A-Za-z0-9+/]{4})$`); !m {
-		errors = append(regexErrs, fmt.Errorf(`%q contains invalid characters, %q must be a valid base64 string.`, k))
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %q to be string", k))
+		return nil, errors
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		if corruptErr, ok := err.(base64.CorruptInputError); ok {
+			errors = append(errors, fmt.Errorf(`%q contains invalid characters, %q must be a valid base64 string, found invalid character at offset %d`, k, k, int64(corruptErr)))
+		} else {
+			errors = append(errors, fmt.Errorf(`%q contains invalid characters, %q must be a valid base64 string: %+v`, k, k, err))
+		}
+
+		return nil, errors
+	}
+
+	if len(decoded) > customBlockResponseBodyMaxBytes {
+		errors = append(errors, fmt.Errorf(`%q is invalid, the decoded value must not exceed %d bytes (32 KB), got %d bytes`, k, customBlockResponseBodyMaxBytes, len(decoded)))
 	}
 
 	return nil, errors
 }
+
+// CustomBlockResponseSettings cross-checks a WAF policy's custom_block_response_body against its sibling
+// custom_block_response_content_type, when both are set. For text/html and application/json content types the
+// decoded body must be valid UTF-8; an application/json body must also be valid JSON, and a text/html body's
+// sniffed content type must be text/html. Other content types are not cross-checked
+func CustomBlockResponseSettings(d *schema.ResourceDiff) error {
+	rawBody, bodyOk := d.GetOk("custom_block_response_body")
+	rawContentType, contentTypeOk := d.GetOk("custom_block_response_content_type")
+	if !bodyOk || !contentTypeOk {
+		return nil
+	}
+
+	return validateCustomBlockResponse(rawBody.(string), rawContentType.(string))
+}
+
+// validateCustomBlockResponse contains the actual validation logic for CustomBlockResponseSettings, split out
+// so it can be unit tested without a *schema.ResourceDiff
+func validateCustomBlockResponse(body, contentType string) error {
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return fmt.Errorf(`"custom_block_response_body" is invalid: %+v`, err)
+	}
+
+	if contentType != "text/html" && contentType != "application/json" {
+		return nil
+	}
+
+	if !utf8.Valid(decoded) {
+		return fmt.Errorf(`"custom_block_response_body" is invalid, the decoded value must be valid UTF-8 when "custom_block_response_content_type" is %q`, contentType)
+	}
+
+	// net/http's content sniffing table has no signature for JSON, so http.DetectContentType never returns
+	// "application/json" - use json.Valid for that content type instead and only sniff for text/html, which
+	// net/http can actually detect
+	if contentType == "application/json" {
+		if !json.Valid(decoded) {
+			return fmt.Errorf(`"custom_block_response_body" is invalid, its content is not valid JSON but "custom_block_response_content_type" is %q`, contentType)
+		}
+
+		return nil
+	}
+
+	sniffLen := len(decoded)
+	if sniffLen > 512 {
+		sniffLen = 512
+	}
+
+	sniffed := http.DetectContentType(decoded[:sniffLen])
+	if !strings.HasPrefix(sniffed, contentType) {
+		return fmt.Errorf(`"custom_block_response_body" is invalid, its content does not appear to be %q, detected %q`, contentType, sniffed)
+	}
+
+	return nil
+}
 func FrontdoorSettings(d *schema.ResourceDiff) error {
 	routingRules := d.Get("routing_rule").([]interface{})
 	configFrontendEndpoints := d.Get("frontend_endpoint").([]interface{})
@@ -103,6 +183,56 @@ func FrontdoorSettings(d *schema.ResourceDiff) error {
 		}
 	}
 
+	// Check 4. validate that no two routing rules define the same "patterns_to_match" set on the same
+	// (frontend_endpoint, accepted_protocol) pair, and warn about rules that can never be reached because another
+	// rule on the same frontend/protocol already matches a strict superset of their patterns. Rules whose pattern
+	// sets only partly overlap are neither rejected nor warned about
+	type patternSet struct {
+		routingRuleName string
+		patterns        map[string]bool
+	}
+	bucketsByFrontendProtocol := make(map[string][]patternSet)
+
+	for _, rr := range routingRules {
+		routingRule := rr.(map[string]interface{})
+		routingRuleName := routingRule["name"].(string)
+
+		frontends := routingRule["frontend_endpoints"].([]interface{})
+		protocols := routingRule["accepted_protocols"].([]interface{})
+		patterns := routingRule["patterns_to_match"].([]interface{})
+
+		current := patternSet{routingRuleName: routingRuleName, patterns: make(map[string]bool)}
+		for _, pat := range patterns {
+			current.patterns[pat.(string)] = true
+		}
+
+		for _, f := range frontends {
+			for _, p := range protocols {
+				frontendName := f.(string)
+				protocol := p.(string)
+				bucketKey := fmt.Sprintf("%s|%s", frontendName, protocol)
+
+				for _, existing := range bucketsByFrontendProtocol[bucketKey] {
+					// Hard error only when the two rules define the exact same "patterns_to_match" set for this
+					// frontend/protocol
+					if isEqualPatternSet(existing.patterns, current.patterns) {
+						return fmt.Errorf(`"routing_rule":%q conflicts with "routing_rule":%q, they both define the same "frontend_endpoint":%q, "accepted_protocols" and "patterns_to_match". Front Door does not allow two routing rules to share the same frontend/protocol/pattern combination`, routingRuleName, existing.routingRuleName, frontendName)
+					}
+
+					// Otherwise, warn if one rule's patterns are a strict superset of the other's on this
+					// frontend/protocol - the narrower rule can never be reached
+					if isStrictPatternSuperset(existing.patterns, current.patterns) {
+						log.Printf(`[WARN] "routing_rule":%q will never be reached, its "patterns_to_match" for "frontend_endpoint":%q and "accepted_protocols":%q are already fully covered by "routing_rule":%q`, routingRuleName, frontendName, protocol, existing.routingRuleName)
+					} else if isStrictPatternSuperset(current.patterns, existing.patterns) {
+						log.Printf(`[WARN] "routing_rule":%q will never be reached, its "patterns_to_match" for "frontend_endpoint":%q and "accepted_protocols":%q are already fully covered by "routing_rule":%q`, existing.routingRuleName, frontendName, protocol, routingRuleName)
+					}
+				}
+
+				bucketsByFrontendProtocol[bucketKey] = append(bucketsByFrontendProtocol[bucketKey], current)
+			}
+		}
+	}
+
 	// Verify backend pool load balancing settings and health probe settings are defined in the resource schema
 	for _, bps := range backendPools {
 		backendPool := bps.(map[string]interface{})
@@ -145,6 +275,16 @@ func FrontdoorSettings(d *schema.ResourceDiff) error {
 			if !found {
 				return fmt.Errorf(`"backend_pool":%q "health_probe_name":%q was not found in the configuration file. verify you have the "backend_pool_health_probe":%q defined in the configuration file`, backendPoolName, backendPoolHealthProbeName, backendPoolHealthProbeName)
 			}
+
+			for _, hps := range healthProbeSettings {
+				healthProbe := hps.(map[string]interface{})
+				if healthProbe["name"] == backendPoolHealthProbeName {
+					if err := helper.ValidateHealthProbeForPool(healthProbe, backendPool); err != nil {
+						return fmt.Errorf(`"backend_pool":%q is invalid. %+v`, backendPoolName, err)
+					}
+					break
+				}
+			}
 		}
 	}
 
@@ -179,6 +319,37 @@ func FrontdoorSettings(d *schema.ResourceDiff) error {
 	return nil
 }
 
+// isEqualPatternSet returns true if a and b contain exactly the same patterns
+func isEqualPatternSet(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for pattern := range a {
+		if !b[pattern] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isStrictPatternSuperset returns true if every pattern in candidate is also present in superset, candidate is
+// non-empty and superset has at least one additional pattern candidate does not have
+func isStrictPatternSuperset(superset, candidate map[string]bool) bool {
+	if len(candidate) == 0 || len(superset) <= len(candidate) {
+		return false
+	}
+
+	for pattern := range candidate {
+		if !superset[pattern] {
+			return false
+		}
+	}
+
+	return true
+}
+
 // NoEmptyStrings validates that the string is not just whitespace characters (equal to [\r\n\t\f\v ])
 func NoEmptyStrings(i interface{}, k string) ([]string, []error) {
 	v, ok := i.(string)
@@ -191,4 +362,139 @@ func NoEmptyStrings(i interface{}, k string) ([]string, []error) {
 	}
 
 	return nil, nil
-}
\ No newline at end of file
+}
+
+// frontDoorWAFExclusionSelectors are the match variables Front Door's WAF supports excluding values from
+var frontDoorWAFExclusionSelectors = []string{
+	"RequestHeaderNames",
+	"RequestCookieNames",
+	"QueryStringArgNames",
+	"RequestBodyPostArgNames",
+}
+
+// frontDoorWAFExclusionMatchOperators are the operators Front Door's WAF supports when matching a managed
+// rule exclusion's selector value
+var frontDoorWAFExclusionMatchOperators = []string{
+	"Equals",
+	"Contains",
+	"StartsWith",
+	"EndsWith",
+	"EqualsAny",
+}
+
+// FrontDoorWAFExclusionSelector validates that a managed rule exclusion's "match_variable" is one of the
+// selectors Front Door's WAF supports excluding values from
+func FrontDoorWAFExclusionSelector(i interface{}, k string) (_ []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %q to be string", k))
+		return nil, errors
+	}
+
+	for _, selector := range frontDoorWAFExclusionSelectors {
+		if v == selector {
+			return nil, nil
+		}
+	}
+
+	errors = append(errors, fmt.Errorf(`%q must be one of %q, got %q`, k, frontDoorWAFExclusionSelectors, v))
+	return nil, errors
+}
+
+// FrontDoorWAFExclusionMatchOperator validates that a managed rule exclusion's "operator" is one of the
+// match operators Front Door's WAF supports
+func FrontDoorWAFExclusionMatchOperator(i interface{}, k string) (_ []string, errors []error) {
+	v, ok := i.(string)
+	if !ok {
+		errors = append(errors, fmt.Errorf("expected type of %q to be string", k))
+		return nil, errors
+	}
+
+	for _, operator := range frontDoorWAFExclusionMatchOperators {
+		if v == operator {
+			return nil, nil
+		}
+	}
+
+	errors = append(errors, fmt.Errorf(`%q must be one of %q, got %q`, k, frontDoorWAFExclusionMatchOperators, v))
+	return nil, errors
+}
+
+// exclusionKey uniquely identifies a managed rule exclusion within the scope it is declared in
+type exclusionKey struct {
+	matchVariable string
+	operator      string
+	selector      string
+}
+
+// validateExclusionBlocks walks a list of "exclusion" blocks belonging to a single enclosing scope (a rule set,
+// a rule group override, or an individual rule) and ensures the (match_variable, operator, selector) triples are
+// unique within that scope and that "EqualsAny" is only ever paired with an empty selector value
+func validateExclusionBlocks(exclusions []interface{}, scopeDescription string) error {
+	seen := make(map[exclusionKey]bool)
+
+	for _, e := range exclusions {
+		exclusion := e.(map[string]interface{})
+		matchVariable := exclusion["match_variable"].(string)
+		operator := exclusion["operator"].(string)
+		selector := exclusion["selector"].(string)
+
+		if operator == "EqualsAny" && selector != "" {
+			return fmt.Errorf(`%s is invalid, "exclusion" "selector" must be empty when "operator" is "EqualsAny", got %q`, scopeDescription, selector)
+		}
+
+		if operator != "EqualsAny" && selector == "" {
+			return fmt.Errorf(`%s is invalid, "exclusion" "selector" must not be empty when "operator" is %q`, scopeDescription, operator)
+		}
+
+		key := exclusionKey{matchVariable: matchVariable, operator: operator, selector: selector}
+		if seen[key] {
+			return fmt.Errorf(`%s is invalid, "exclusion" defines the same "match_variable":%q, "operator":%q and "selector":%q more than once`, scopeDescription, matchVariable, operator, selector)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// ValidateManagedRuleExclusions walks every "managed_rule" block's rule-set-level, rule-group-override-level and
+// individual-rule-level "exclusion" blocks and validates them, analogous to how FrontdoorSettings validates the
+// whole Front Door configuration
+func ValidateManagedRuleExclusions(d *schema.ResourceDiff) error {
+	managedRules := d.Get("managed_rule").([]interface{})
+
+	for _, mr := range managedRules {
+		managedRule := mr.(map[string]interface{})
+		ruleSetType := managedRule["type"].(string)
+		ruleSetVersion := managedRule["version"].(string)
+		ruleSetDescription := fmt.Sprintf(`"managed_rule" %q (version %q)`, ruleSetType, ruleSetVersion)
+
+		if err := validateExclusionBlocks(managedRule["exclusion"].([]interface{}), ruleSetDescription); err != nil {
+			return err
+		}
+
+		overrides := managedRule["override"].([]interface{})
+		for _, o := range overrides {
+			override := o.(map[string]interface{})
+			ruleGroupName := override["rule_group_name"].(string)
+			ruleGroupDescription := fmt.Sprintf(`%s "override" "rule_group_name":%q`, ruleSetDescription, ruleGroupName)
+
+			if err := validateExclusionBlocks(override["exclusion"].([]interface{}), ruleGroupDescription); err != nil {
+				return err
+			}
+
+			rules := override["rule"].([]interface{})
+			for _, r := range rules {
+				rule := r.(map[string]interface{})
+				ruleID := rule["rule_id"].(string)
+				ruleDescription := fmt.Sprintf(`%s "rule" "rule_id":%q`, ruleGroupDescription, ruleID)
+
+				if err := validateExclusionBlocks(rule["exclusion"].([]interface{}), ruleDescription); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package validate
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCustomBlockResponseBody(t *testing.T) {
+	oversized := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", customBlockResponseBodyMaxBytes+1)))
+
+	cases := []struct {
+		Name     string
+		Input    string
+		ErrCount int
+	}{
+		{
+			Name:     "empty string",
+			Input:    "",
+			ErrCount: 0,
+		},
+		{
+			Name:     "valid base64",
+			Input:    base64.StdEncoding.EncodeToString([]byte("<html><body>Blocked</body></html>")),
+			ErrCount: 0,
+		},
+		{
+			Name:     "invalid base64 characters",
+			Input:    "not-valid-base64!!",
+			ErrCount: 1,
+		},
+		{
+			Name:     "not correctly padded",
+			Input:    "YQ",
+			ErrCount: 1,
+		},
+		{
+			Name:     "decoded value exceeds 32 KB",
+			Input:    oversized,
+			ErrCount: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, errors := CustomBlockResponseBody(tc.Input, "custom_block_response_body")
+
+			if len(errors) != tc.ErrCount {
+				t.Fatalf("expected %d errors for input %q but got %d: %v", tc.ErrCount, tc.Input, len(errors), errors)
+			}
+		})
+	}
+}
+
+func TestIsEqualPatternSet(t *testing.T) {
+	a := map[string]bool{"/foo/*": true, "/bar/*": true}
+	equalToA := map[string]bool{"/bar/*": true, "/foo/*": true}
+	supersetOfA := map[string]bool{"/foo/*": true, "/bar/*": true, "/baz/*": true}
+	disjointFromA := map[string]bool{"/qux/*": true}
+
+	if !isEqualPatternSet(a, equalToA) {
+		t.Fatalf("expected %v and %v to be equal pattern sets", a, equalToA)
+	}
+	if isEqualPatternSet(a, supersetOfA) {
+		t.Fatalf("did not expect %v and %v to be equal pattern sets", a, supersetOfA)
+	}
+	if isEqualPatternSet(a, disjointFromA) {
+		t.Fatalf("did not expect %v and %v to be equal pattern sets", a, disjointFromA)
+	}
+
+	// the same pair is a strict superset relationship, which is what the unreachable-rule warning fires on; with
+	// the not-equal check above, this keeps the warning branch reachable instead of it being an equal-set collision
+	if !isStrictPatternSuperset(supersetOfA, a) {
+		t.Fatalf("expected %v to be a strict superset of %v", supersetOfA, a)
+	}
+}
+
+func TestValidateCustomBlockResponse(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Body        string
+		ContentType string
+		ExpectErr   bool
+	}{
+		{
+			Name:        "valid json body with application/json",
+			Body:        base64.StdEncoding.EncodeToString([]byte(`{"error":"blocked"}`)),
+			ContentType: "application/json",
+			ExpectErr:   false,
+		},
+		{
+			Name:        "invalid json body with application/json",
+			Body:        base64.StdEncoding.EncodeToString([]byte(`not json`)),
+			ContentType: "application/json",
+			ExpectErr:   true,
+		},
+		{
+			Name:        "valid html body with text/html",
+			Body:        base64.StdEncoding.EncodeToString([]byte("<html><body>Blocked</body></html>")),
+			ContentType: "text/html",
+			ExpectErr:   false,
+		},
+		{
+			Name:        "json body declared as text/html",
+			Body:        base64.StdEncoding.EncodeToString([]byte(`{"error":"blocked"}`)),
+			ContentType: "text/html",
+			ExpectErr:   true,
+		},
+		{
+			Name:        "non-UTF8 body with text/html",
+			Body:        base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe, 0xfd}),
+			ContentType: "text/html",
+			ExpectErr:   true,
+		},
+		{
+			Name:        "content type outside the checked set is not cross-validated",
+			Body:        base64.StdEncoding.EncodeToString([]byte(`anything`)),
+			ContentType: "text/plain",
+			ExpectErr:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := validateCustomBlockResponse(tc.Body, tc.ContentType)
+
+			if tc.ExpectErr && err == nil {
+				t.Fatalf("expected an error but got none")
+			}
+			if !tc.ExpectErr && err != nil {
+				t.Fatalf("expected no error but got: %+v", err)
+			}
+		})
+	}
+}
+
+func TestFrontDoorWAFExclusionSelector(t *testing.T) {
+	cases := []struct {
+		Input    string
+		ErrCount int
+	}{
+		{Input: "RequestHeaderNames", ErrCount: 0},
+		{Input: "RequestCookieNames", ErrCount: 0},
+		{Input: "QueryStringArgNames", ErrCount: 0},
+		{Input: "RequestBodyPostArgNames", ErrCount: 0},
+		{Input: "NotARealSelector", ErrCount: 1},
+		{Input: "", ErrCount: 1},
+	}
+
+	for _, tc := range cases {
+		_, errors := FrontDoorWAFExclusionSelector(tc.Input, "match_variable")
+
+		if len(errors) != tc.ErrCount {
+			t.Fatalf("expected %d errors for input %q but got %d: %v", tc.ErrCount, tc.Input, len(errors), errors)
+		}
+	}
+}
+
+func TestFrontDoorWAFExclusionMatchOperator(t *testing.T) {
+	cases := []struct {
+		Input    string
+		ErrCount int
+	}{
+		{Input: "Equals", ErrCount: 0},
+		{Input: "Contains", ErrCount: 0},
+		{Input: "StartsWith", ErrCount: 0},
+		{Input: "EndsWith", ErrCount: 0},
+		{Input: "EqualsAny", ErrCount: 0},
+		{Input: "NotARealOperator", ErrCount: 1},
+	}
+
+	for _, tc := range cases {
+		_, errors := FrontDoorWAFExclusionMatchOperator(tc.Input, "operator")
+
+		if len(errors) != tc.ErrCount {
+			t.Fatalf("expected %d errors for input %q but got %d: %v", tc.ErrCount, tc.Input, len(errors), errors)
+		}
+	}
+}
+
+func TestValidateExclusionBlocks(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Exclusions []interface{}
+		ExpectErr  bool
+	}{
+		{
+			Name: "valid single exclusion",
+			Exclusions: []interface{}{
+				map[string]interface{}{"match_variable": "RequestHeaderNames", "operator": "Equals", "selector": "x-foo"},
+			},
+			ExpectErr: false,
+		},
+		{
+			Name: "EqualsAny with empty selector is valid",
+			Exclusions: []interface{}{
+				map[string]interface{}{"match_variable": "RequestCookieNames", "operator": "EqualsAny", "selector": ""},
+			},
+			ExpectErr: false,
+		},
+		{
+			Name: "EqualsAny with a selector value is invalid",
+			Exclusions: []interface{}{
+				map[string]interface{}{"match_variable": "RequestCookieNames", "operator": "EqualsAny", "selector": "session"},
+			},
+			ExpectErr: true,
+		},
+		{
+			Name: "non-EqualsAny with an empty selector is invalid",
+			Exclusions: []interface{}{
+				map[string]interface{}{"match_variable": "RequestCookieNames", "operator": "Equals", "selector": ""},
+			},
+			ExpectErr: true,
+		},
+		{
+			Name: "duplicate triples are invalid",
+			Exclusions: []interface{}{
+				map[string]interface{}{"match_variable": "RequestHeaderNames", "operator": "Equals", "selector": "x-foo"},
+				map[string]interface{}{"match_variable": "RequestHeaderNames", "operator": "Equals", "selector": "x-foo"},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := validateExclusionBlocks(tc.Exclusions, `"managed_rule" "test"`)
+
+			if tc.ExpectErr && err == nil {
+				t.Fatalf("expected an error but got none")
+			}
+			if !tc.ExpectErr && err != nil {
+				t.Fatalf("expected no error but got: %+v", err)
+			}
+		})
+	}
+}